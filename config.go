@@ -0,0 +1,148 @@
+package stats
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// defaultResetInterval is how often the current-interval counters roll over
+// when Config.ResetInterval is unset.
+const defaultResetInterval = time.Second
+
+// defaultNumBuckets is how many past intervals are kept for
+// Data().RecentIntervals when Config.NumBuckets is unset.
+const defaultNumBuckets = 60
+
+// Config configures the background collector started by NewWithConfig.
+type Config struct {
+	// ResetInterval is how often the current interval's counters roll over
+	// into intervalBuckets. Defaults to one second.
+	ResetInterval time.Duration
+
+	// Clock returns the current time for Uptime and response-time
+	// calculations. Overridable for tests; defaults to time.Now. The
+	// collector's rotation ticker always runs on the real wall clock.
+	Clock func() time.Time
+
+	// NumBuckets is how many completed intervals Data().RecentIntervals
+	// reports alongside the current one. Defaults to 60.
+	NumBuckets int
+
+	// RouteExtractor derives the route label requests are grouped under for
+	// the per-route breakdown. Defaults to DefaultRouteExtractor.
+	RouteExtractor RouteExtractor
+
+	// MaxCardinality caps how many distinct (method, route) pairs are
+	// tracked before extra routes fold into otherRouteBucket. Defaults to
+	// defaultMaxCardinality.
+	MaxCardinality int
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.ResetInterval <= 0 {
+		cfg.ResetInterval = defaultResetInterval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	if cfg.NumBuckets <= 0 {
+		cfg.NumBuckets = defaultNumBuckets
+	}
+	if cfg.RouteExtractor == nil {
+		cfg.RouteExtractor = DefaultRouteExtractor
+	}
+	if cfg.MaxCardinality <= 0 {
+		cfg.MaxCardinality = defaultMaxCardinality
+	}
+	return cfg
+}
+
+// NewWithConfig constructs a Stats structure whose background collector can
+// be stopped with Close, instead of running for the life of the process.
+func NewWithConfig(cfg Config) *Stats {
+	cfg = cfg.withDefaults()
+	now := cfg.Clock()
+
+	intervalBuckets := make([]map[string]int, cfg.NumBuckets)
+	for i := range intervalBuckets {
+		intervalBuckets[i] = map[string]int{}
+	}
+
+	stats := &Stats{
+		Uptime:              now,
+		Pid:                 os.Getpid(),
+		ResponseCounts:      map[string]int{},
+		TotalResponseCounts: map[string]int{},
+		TotalResponseTime:   time.Time{},
+		BytesInByStatus:     map[string]int64{},
+		BytesOutByStatus:    map[string]int64{},
+		lastReset:           now,
+		windows: []*latencyWindow{
+			newLatencyWindow("1m", time.Second, 60),
+			newLatencyWindow("5m", 5*time.Second, 60),
+			newLatencyWindow("1h", time.Minute, 60),
+		},
+		routeExtractor:  cfg.RouteExtractor,
+		maxCardinality:  cfg.MaxCardinality,
+		routeStats:      map[string]*routeAccumulator{},
+		clock:           cfg.Clock,
+		resetInterval:   cfg.ResetInterval,
+		intervalBuckets: intervalBuckets,
+		stopCh:          make(chan struct{}),
+		doneCh:          make(chan struct{}),
+	}
+	for c := range stats.allTime {
+		stats.allTime[c] = newHistogram()
+	}
+
+	go stats.collect()
+
+	return stats
+}
+
+// collect rotates the current interval into intervalBuckets and advances the
+// latency windows every ResetInterval, until Close stops it.
+func (mw *Stats) collect() {
+	defer close(mw.doneCh)
+
+	ticker := time.NewTicker(mw.resetInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mw.stopCh:
+			return
+		case now := <-ticker.C:
+			mw.rotate()
+			for _, w := range mw.windows {
+				w.advance(now)
+			}
+		}
+	}
+}
+
+// rotate pushes the current interval's ResponseCounts into intervalBuckets
+// and starts a fresh interval, without discarding what was just measured.
+func (mw *Stats) rotate() {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	mw.intervalBuckets[mw.intervalPos] = mw.ResponseCounts
+	mw.intervalPos = (mw.intervalPos + 1) % len(mw.intervalBuckets)
+
+	mw.ResponseCounts = map[string]int{}
+	mw.lastReset = mw.now()
+	atomic.StoreInt64(&mw.currentBytesIn, 0)
+	atomic.StoreInt64(&mw.currentBytesOut, 0)
+}
+
+// Close stops the background collector goroutine and waits for it to exit.
+// It is safe to call more than once.
+func (mw *Stats) Close() error {
+	mw.stopOnce.Do(func() {
+		close(mw.stopCh)
+	})
+	<-mw.doneCh
+	return nil
+}
@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotateFillsIntervalBuckets(t *testing.T) {
+	mw := NewWithConfig(Config{NumBuckets: 3})
+	defer mw.Close()
+
+	mw.mu.Lock()
+	mw.ResponseCounts["200"] = 5
+	mw.mu.Unlock()
+
+	mw.rotate()
+
+	mw.mu.RLock()
+	defer mw.mu.RUnlock()
+
+	if got := mw.intervalBuckets[0]["200"]; got != 5 {
+		t.Errorf("intervalBuckets[0][\"200\"] = %d, want 5", got)
+	}
+	if n := mw.ResponseCounts["200"]; n != 0 {
+		t.Errorf("ResponseCounts[\"200\"] after rotate = %d, want 0", n)
+	}
+}
+
+func TestClose(t *testing.T) {
+	mw := NewWithConfig(Config{ResetInterval: time.Millisecond})
+
+	if err := mw.Close(); err != nil {
+		t.Fatalf("Close() = %v, want nil", err)
+	}
+
+	select {
+	case <-mw.doneCh:
+	default:
+		t.Fatal("collect goroutine did not exit after Close")
+	}
+
+	// Close must be idempotent.
+	if err := mw.Close(); err != nil {
+		t.Fatalf("second Close() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,216 @@
+package stats
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minLatency/maxLatency/latencyGrowthFactor define the log-spaced latency bins, 1µs to 60s with ~5% growth per bin.
+const (
+	minLatency          = time.Microsecond
+	maxLatency          = 60 * time.Second
+	latencyGrowthFactor = 1.05
+)
+
+var numLatencyBins = int(math.Log(float64(maxLatency)/float64(minLatency))/math.Log(latencyGrowthFactor)) + 1
+
+// latencyBinIndex maps a duration to its histogram bin.
+func latencyBinIndex(d time.Duration) int {
+	if d < minLatency {
+		d = minLatency
+	}
+	idx := int(math.Log(float64(d)/float64(minLatency)) / math.Log(latencyGrowthFactor))
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= numLatencyBins {
+		idx = numLatencyBins - 1
+	}
+	return idx
+}
+
+// latencyBinUpperBound is the inverse of latencyBinIndex: the upper edge of
+// bin i, used as the reported value for any sample that landed in it.
+func latencyBinUpperBound(i int) time.Duration {
+	return time.Duration(float64(minLatency) * math.Pow(latencyGrowthFactor, float64(i+1)))
+}
+
+// histogram is a set of exponentially spaced latency bins, counted atomically.
+type histogram struct {
+	counts []uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, numLatencyBins)}
+}
+
+func (h *histogram) record(d time.Duration) {
+	atomic.AddUint64(&h.counts[latencyBinIndex(d)], 1)
+}
+
+func (h *histogram) reset() {
+	for i := range h.counts {
+		atomic.StoreUint64(&h.counts[i], 0)
+	}
+}
+
+// merge adds o's counts into h, bin by bin.
+func (h *histogram) merge(o *histogram) {
+	for i := range h.counts {
+		atomic.AddUint64(&h.counts[i], atomic.LoadUint64(&o.counts[i]))
+	}
+}
+
+func (h *histogram) total() uint64 {
+	var sum uint64
+	for i := range h.counts {
+		sum += atomic.LoadUint64(&h.counts[i])
+	}
+	return sum
+}
+
+// percentile walks the bins until the cumulative count crosses q*total and
+// returns that bin's upper bound.
+func (h *histogram) percentile(q float64) time.Duration {
+	total := h.total()
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(total)))
+	if target == 0 {
+		// q<=0: report the minimum observed value, i.e. the first bin with
+		// any samples, rather than matching bin 0 on an empty cumulative sum.
+		target = 1
+	}
+	var cum uint64
+	for i := range h.counts {
+		cum += atomic.LoadUint64(&h.counts[i])
+		if cum >= target {
+			return latencyBinUpperBound(i)
+		}
+	}
+	return latencyBinUpperBound(numLatencyBins - 1)
+}
+
+func (h *histogram) max() time.Duration {
+	for i := numLatencyBins - 1; i >= 0; i-- {
+		if atomic.LoadUint64(&h.counts[i]) > 0 {
+			return latencyBinUpperBound(i)
+		}
+	}
+	return 0
+}
+
+// statusClass buckets a response into one of the coarse classes Data reports
+// latency for, in addition to the overall figures.
+type statusClass int
+
+const (
+	classOverall statusClass = iota
+	class2xx
+	class4xx
+	class5xx
+	numStatusClasses
+)
+
+var statusClassNames = [numStatusClasses]string{
+	classOverall: "overall",
+	class2xx:     "2xx",
+	class4xx:     "4xx",
+	class5xx:     "5xx",
+}
+
+func classify(status int) statusClass {
+	switch {
+	case status >= 200 && status < 300:
+		return class2xx
+	case status >= 400 && status < 500:
+		return class4xx
+	case status >= 500:
+		return class5xx
+	default:
+		return numStatusClasses // no class match besides overall
+	}
+}
+
+// latencyWindow is a ring of bucketed histograms covering a fixed span of
+// time, e.g. the last minute as 60 one-second buckets.
+type latencyWindow struct {
+	label      string
+	bucketSpan time.Duration
+
+	mu          sync.Mutex
+	buckets     [][numStatusClasses]*histogram
+	pos         int
+	lastAdvance time.Time
+}
+
+func newLatencyWindow(label string, bucketSpan time.Duration, numBuckets int) *latencyWindow {
+	buckets := make([][numStatusClasses]*histogram, numBuckets)
+	for i := range buckets {
+		for c := range buckets[i] {
+			buckets[i][c] = newHistogram()
+		}
+	}
+	return &latencyWindow{label: label, bucketSpan: bucketSpan, buckets: buckets}
+}
+
+// span is the total duration covered by the window.
+func (w *latencyWindow) span() time.Duration {
+	return w.bucketSpan * time.Duration(len(w.buckets))
+}
+
+func (w *latencyWindow) record(status int, d time.Duration) {
+	w.mu.Lock()
+	cur := w.buckets[w.pos]
+	w.mu.Unlock()
+
+	cur[classOverall].record(d)
+	if c := classify(status); c < numStatusClasses {
+		cur[c].record(d)
+	}
+}
+
+// advance is called by the background collector on every tick, regardless
+// of Config.ResetInterval, and rotates to the next bucket (clearing it) once
+// per bucketSpan of real elapsed time - so window semantics don't depend on
+// how often the collector happens to run.
+func (w *latencyWindow) advance(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lastAdvance.IsZero() {
+		w.lastAdvance = now
+		return
+	}
+
+	steps := int(now.Sub(w.lastAdvance) / w.bucketSpan)
+	if steps <= 0 {
+		return
+	}
+	if steps > len(w.buckets) {
+		steps = len(w.buckets)
+	}
+	w.lastAdvance = w.lastAdvance.Add(time.Duration(steps) * w.bucketSpan)
+
+	for i := 0; i < steps; i++ {
+		w.pos = (w.pos + 1) % len(w.buckets)
+		for _, h := range w.buckets[w.pos] {
+			h.reset()
+		}
+	}
+}
+
+// merged sums every bucket's histogram for the given class into one.
+func (w *latencyWindow) merged(c statusClass) *histogram {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	out := newHistogram()
+	for _, bucket := range w.buckets {
+		out.merge(bucket[c])
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	h := newHistogram()
+	h.record(5 * time.Second)
+	h.record(6 * time.Second)
+
+	if got := h.percentile(0); got < 4*time.Second || got > 6*time.Second {
+		t.Errorf("percentile(0) = %v, want the minimum observed sample (~5s), not the first bin", got)
+	}
+	if got := h.percentile(1); got < 6*time.Second {
+		t.Errorf("percentile(1) = %v, want at least the maximum observed sample (~6s)", got)
+	}
+}
+
+func TestHistogramPercentileEmpty(t *testing.T) {
+	h := newHistogram()
+	if got := h.percentile(0.5); got != 0 {
+		t.Errorf("percentile on an empty histogram = %v, want 0", got)
+	}
+}
+
+func TestHistogramMax(t *testing.T) {
+	h := newHistogram()
+	h.record(time.Millisecond)
+	h.record(2 * time.Second)
+
+	if got := h.max(); got < 2*time.Second {
+		t.Errorf("max() = %v, want at least 2s", got)
+	}
+}
+
+func TestLatencyBinIndexMonotonic(t *testing.T) {
+	prev := latencyBinIndex(minLatency)
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, time.Second, maxLatency} {
+		idx := latencyBinIndex(d)
+		if idx < prev {
+			t.Errorf("latencyBinIndex(%v) = %d, want >= previous index %d", d, idx, prev)
+		}
+		prev = idx
+	}
+}
+
+func TestLatencyBinIndexClamped(t *testing.T) {
+	if idx := latencyBinIndex(-time.Second); idx != 0 {
+		t.Errorf("latencyBinIndex(negative) = %d, want 0", idx)
+	}
+	if idx := latencyBinIndex(10 * maxLatency); idx != numLatencyBins-1 {
+		t.Errorf("latencyBinIndex(beyond max) = %d, want %d", idx, numLatencyBins-1)
+	}
+}
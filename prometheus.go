@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// JSONHandler serves the same structure Data() returns as application/json,
+// so callers can wire it up as e.g. GET /stats.json in one line.
+func (mw *Stats) JSONHandler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		body, err := json.Marshal(mw.Data())
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+			return
+		}
+		ctx.SetContentType("application/json; charset=utf-8")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		ctx.SetBody(body)
+	}
+}
+
+// PrometheusHandler serves the current stats in Prometheus text exposition
+// format, so callers can wire it up as e.g. GET /metrics in one line.
+func (mw *Stats) PrometheusHandler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain; version=0.0.4; charset=utf-8")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		ctx.SetBody(mw.PrometheusMetrics())
+	}
+}
+
+// PrometheusMetrics renders the current stats as Prometheus text exposition
+// format, built directly against a bytes.Buffer from the same structures
+// Data() uses (no dependency on the Prometheus client library).
+func (mw *Stats) PrometheusMetrics() []byte {
+	data := mw.Data()
+
+	var buf bytes.Buffer
+
+	writeMetricHeader(&buf, "process_pid", "gauge", "Process ID of this process.")
+	fmt.Fprintf(&buf, "process_pid %d\n", data.Pid)
+
+	writeMetricHeader(&buf, "process_start_time_seconds", "gauge", "Start time of the process since unix epoch, in seconds.")
+	fmt.Fprintf(&buf, "process_start_time_seconds %d\n", mw.Uptime.Unix())
+
+	writeMetricHeader(&buf, "process_uptime_seconds", "gauge", "Time since the process started, in seconds.")
+	fmt.Fprintf(&buf, "process_uptime_seconds %f\n", data.UpTimeSec)
+
+	writeMetricHeader(&buf, "http_requests_total", "counter", "Total number of HTTP requests by method, route and status code.")
+	methods := make([]string, 0, len(data.Routes))
+	for method := range data.Routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		byRoute := data.Routes[method]
+		routeNames := make([]string, 0, len(byRoute))
+		for route := range byRoute {
+			routeNames = append(routeNames, route)
+		}
+		sort.Strings(routeNames)
+		for _, route := range routeNames {
+			codes := make([]string, 0, len(byRoute[route].StatusCounts))
+			for code := range byRoute[route].StatusCounts {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+			for _, code := range codes {
+				fmt.Fprintf(&buf, "http_requests_total{method=%s,route=%s,code=%s} %d\n",
+					quoteLabel(method), quoteLabel(route), quoteLabel(code), byRoute[route].StatusCounts[code])
+			}
+		}
+	}
+
+	writeMetricHeader(&buf, "http_request_duration_seconds", "summary", "HTTP request duration in seconds.")
+	fmt.Fprintf(&buf, "http_request_duration_seconds{quantile=\"0.5\"} %f\n", data.P50ResponseTimeSec)
+	fmt.Fprintf(&buf, "http_request_duration_seconds{quantile=\"0.95\"} %f\n", data.P95ResponseTimeSec)
+	fmt.Fprintf(&buf, "http_request_duration_seconds{quantile=\"0.99\"} %f\n", data.P99ResponseTimeSec)
+	fmt.Fprintf(&buf, "http_request_duration_seconds_sum %f\n", data.TotalResponseTimeSec)
+	fmt.Fprintf(&buf, "http_request_duration_seconds_count %d\n", data.TotalCount)
+
+	writeMetricHeader(&buf, "http_response_size_bytes", "summary", "HTTP response size in bytes.")
+	fmt.Fprintf(&buf, "http_response_size_bytes_sum %d\n", data.TotalBytesOut)
+	fmt.Fprintf(&buf, "http_response_size_bytes_count %d\n", data.TotalCount)
+
+	return buf.Bytes()
+}
+
+// writeMetricHeader writes the "# HELP" and "# TYPE" comment lines that
+// precede a metric family in the Prometheus text format.
+func writeMetricHeader(buf *bytes.Buffer, name, metricType, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, metricType)
+}
+
+// quoteLabel escapes and quotes a label value per the Prometheus text format.
+func quoteLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
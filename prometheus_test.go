@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetricsIncludesExpectedFamilies(t *testing.T) {
+	mw := NewWithConfig(Config{Clock: func() time.Time { return time.Unix(0, 0) }})
+	defer mw.Close()
+
+	mw.EndWithOptions(time.Unix(0, 0), Options{StatusCode: 200, Method: "GET", Route: "/users", BytesOut: 42})
+
+	out := string(mw.PrometheusMetrics())
+
+	for _, want := range []string{
+		"# HELP http_requests_total",
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/users",code="200"} 1`,
+		"# HELP http_request_duration_seconds",
+		"# TYPE http_request_duration_seconds summary",
+		"http_request_duration_seconds_count 1",
+		"# HELP http_response_size_bytes",
+		"http_response_size_bytes_sum 42",
+		"# HELP process_uptime_seconds",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PrometheusMetrics() missing %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestQuoteLabelEscapes(t *testing.T) {
+	got := quoteLabel(`a"b\c` + "\n")
+	want := `"a\"b\\c\n"`
+	if got != want {
+		t.Errorf("quoteLabel = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultMaxCardinality bounds how many distinct (method, route) pairs are
+// tracked before further routes are folded into otherRouteBucket, so an
+// attacker who controls the request path can't grow the route maps without
+// bound.
+const defaultMaxCardinality = 1000
+
+// otherRouteBucket is the route name used once MaxCardinality distinct
+// (method, route) pairs, counted across all methods, are already tracked.
+const otherRouteBucket = "__other__"
+
+// RouteExtractor derives the route label to group stats under for a given
+// request, e.g. a templated path like "/users/:id" rather than the literal
+// path.
+type RouteExtractor func(ctx *fasthttp.RequestCtx) string
+
+// DefaultRouteExtractor uses the literal request path as the route label.
+func DefaultRouteExtractor(ctx *fasthttp.RequestCtx) string {
+	return string(ctx.Path())
+}
+
+// RouteFromUserValue returns a RouteExtractor that reads the matched route
+// pattern from ctx.UserValue(key) - the convention routers such as
+// fasthttp/router use to expose the templated path (e.g. "/users/:id")
+// alongside the literal one. Falls back to DefaultRouteExtractor when the
+// user value isn't set.
+func RouteFromUserValue(key string) RouteExtractor {
+	return func(ctx *fasthttp.RequestCtx) string {
+		if v, ok := ctx.UserValue(key).(string); ok && v != "" {
+			return v
+		}
+		return DefaultRouteExtractor(ctx)
+	}
+}
+
+// routeAccumulator holds the running totals for one (method, route) pair.
+// Protected by Stats.mu, same as the other count maps.
+type routeAccumulator struct {
+	method       string
+	route        string
+	count        int64
+	errorCount   int64
+	statusCounts map[string]int64
+	totalTime    time.Duration
+	bytesIn      int64
+	bytesOut     int64
+}
+
+// RouteStat is a serializable snapshot of a routeAccumulator, and the
+// element type returned by TopRoutes.
+type RouteStat struct {
+	Method                 string           `json:"method"`
+	Route                  string           `json:"route"`
+	Count                  int64            `json:"count"`
+	ErrorCount             int64            `json:"error_count"`
+	StatusCounts           map[string]int64 `json:"status_counts"`
+	TotalResponseTimeSec   float64          `json:"total_response_time_sec"`
+	AverageResponseTimeSec float64          `json:"average_response_time_sec"`
+	TotalBytesIn           int64            `json:"total_bytes_in"`
+	TotalBytesOut          int64            `json:"total_bytes_out"`
+}
+
+func (a *routeAccumulator) snapshot() RouteStat {
+	statusCounts := make(map[string]int64, len(a.statusCounts))
+	for code, n := range a.statusCounts {
+		statusCounts[code] = n
+	}
+
+	var avg float64
+	if a.count > 0 {
+		avg = a.totalTime.Seconds() / float64(a.count)
+	}
+
+	return RouteStat{
+		Method:                 a.method,
+		Route:                  a.route,
+		Count:                  a.count,
+		ErrorCount:             a.errorCount,
+		StatusCounts:           statusCounts,
+		TotalResponseTimeSec:   a.totalTime.Seconds(),
+		AverageResponseTimeSec: avg,
+		TotalBytesIn:           a.bytesIn,
+		TotalBytesOut:          a.bytesOut,
+	}
+}
+
+// recordRoute folds opts into the (method, route) bucket the request belongs
+// to, applying the MaxCardinality guard. Callers must hold mw.mu.
+func (mw *Stats) recordRoute(opts Options, statusCode string, responseTime time.Duration) {
+	if opts.Method == "" && opts.Route == "" {
+		return
+	}
+
+	route := opts.Route
+	key := opts.Method + " " + route
+	if _, ok := mw.routeStats[key]; !ok && len(mw.routeStats) >= mw.maxCardinality {
+		route = otherRouteBucket
+		key = opts.Method + " " + route
+	}
+
+	acc, ok := mw.routeStats[key]
+	if !ok {
+		acc = &routeAccumulator{
+			method:       opts.Method,
+			route:        route,
+			statusCounts: map[string]int64{},
+		}
+		mw.routeStats[key] = acc
+	}
+
+	acc.count++
+	acc.statusCounts[statusCode]++
+	acc.totalTime += responseTime
+	acc.bytesIn += opts.BytesIn
+	acc.bytesOut += opts.BytesOut
+	if len(statusCode) > 0 && (statusCode[0] == '4' || statusCode[0] == '5') {
+		acc.errorCount++
+	}
+}
+
+// routeSnapshot returns a stable copy of every tracked route's stats.
+func (mw *Stats) routeSnapshot() []RouteStat {
+	mw.mu.RLock()
+	defer mw.mu.RUnlock()
+
+	routes := make([]RouteStat, 0, len(mw.routeStats))
+	for _, acc := range mw.routeStats {
+		routes = append(routes, acc.snapshot())
+	}
+	return routes
+}
+
+// TopRoutes returns up to n routes ordered by by ("count", "errors", or
+// "latency"; anything else defaults to "count").
+func (mw *Stats) TopRoutes(n int, by string) []RouteStat {
+	routes := mw.routeSnapshot()
+
+	sort.Slice(routes, func(i, j int) bool {
+		switch by {
+		case "errors":
+			return routes[i].ErrorCount > routes[j].ErrorCount
+		case "latency":
+			return routes[i].AverageResponseTimeSec > routes[j].AverageResponseTimeSec
+		default:
+			return routes[i].Count > routes[j].Count
+		}
+	})
+
+	if n < 0 {
+		n = 0
+	}
+	if n < len(routes) {
+		routes = routes[:n]
+	}
+	return routes
+}
@@ -0,0 +1,45 @@
+package stats
+
+import "testing"
+
+func TestRecordRouteFoldsExcessIntoOtherBucket(t *testing.T) {
+	mw := NewWithConfig(Config{MaxCardinality: 2})
+	defer mw.Close()
+
+	mw.mu.Lock()
+	mw.recordRoute(Options{Method: "GET", Route: "/a"}, "200", 0)
+	mw.recordRoute(Options{Method: "GET", Route: "/b"}, "200", 0)
+	mw.recordRoute(Options{Method: "GET", Route: "/c"}, "200", 0)
+	mw.mu.Unlock()
+
+	routes := mw.routeSnapshot()
+	if len(routes) != 3 {
+		t.Fatalf("got %d distinct routes, want 3 (MaxCardinality + __other__)", len(routes))
+	}
+
+	var sawOther bool
+	for _, r := range routes {
+		if r.Route == otherRouteBucket {
+			sawOther = true
+			if r.Count != 1 {
+				t.Errorf("__other__ count = %d, want 1", r.Count)
+			}
+		}
+	}
+	if !sawOther {
+		t.Error("expected the third route to fold into __other__, it did not")
+	}
+}
+
+func TestTopRoutesNegativeN(t *testing.T) {
+	mw := NewWithConfig(Config{})
+	defer mw.Close()
+
+	mw.mu.Lock()
+	mw.recordRoute(Options{Method: "GET", Route: "/a"}, "200", 0)
+	mw.mu.Unlock()
+
+	if routes := mw.TopRoutes(-1, "count"); len(routes) != 0 {
+		t.Errorf("TopRoutes(-1, ...) returned %d routes, want 0", len(routes))
+	}
+}
@@ -2,9 +2,10 @@ package stats
 
 import (
 	"fmt"
-	"os"
 	"sync"
+	"sync/atomic"
 	"time"
+
 	"github.com/valyala/fasthttp"
 )
 
@@ -16,34 +17,74 @@ type Stats struct {
 	ResponseCounts      map[string]int
 	TotalResponseCounts map[string]int
 	TotalResponseTime   time.Time
-}
 
-// New constructs a new Stats structure
-func New() *Stats {
-	stats := &Stats{
-		Uptime:              time.Now(),
-		Pid:                 os.Getpid(),
-		ResponseCounts:      map[string]int{},
-		TotalResponseCounts: map[string]int{},
-		TotalResponseTime:   time.Time{},
-	}
+	// BytesInByStatus and BytesOutByStatus track request/response body+header
+	// bytes broken down by status code. Protected by mu, same as the count
+	// maps above.
+	BytesInByStatus  map[string]int64
+	BytesOutByStatus map[string]int64
 
-	go func() {
-		for {
-			stats.ResetResponseCounts()
+	// lastReset is when ResponseCounts (and the current-window byte
+	// counters below) were last zeroed, used to compute a bytes/sec rate.
+	lastReset time.Time
 
-			time.Sleep(time.Second * 1)
-		}
-	}()
+	// totalBytesIn/Out and currentBytesIn/Out are updated with atomic ops
+	// rather than mu, since byte accounting happens on every request and
+	// shouldn't contend with readers of Data().
+	totalBytesIn    int64
+	totalBytesOut   int64
+	currentBytesIn  int64
+	currentBytesOut int64
+
+	// allTime holds one histogram per status class, accumulated for the
+	// life of the process. windows holds the same breakdown over rolling
+	// 1 minute / 5 minute / 1 hour spans.
+	allTime [numStatusClasses]*histogram
+	windows []*latencyWindow
+
+	// routeExtractor and maxCardinality are set once at construction time
+	// (via Config) and read without mu, since they never change afterwards.
+	routeExtractor RouteExtractor
+	maxCardinality int
+
+	// routeStats holds the per-(method, route) accumulators, protected by mu.
+	routeStats map[string]*routeAccumulator
+
+	// clock returns the current time; overridable via Config for tests.
+	clock func() time.Time
 
-	return stats
+	// resetInterval is how often intervalBuckets rotates.
+	resetInterval time.Duration
+
+	// intervalBuckets is a ring of past ResponseCounts snapshots, one per
+	// completed reset interval, oldest at intervalPos. Protected by mu.
+	intervalBuckets []map[string]int
+	intervalPos     int
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// New constructs a new Stats structure with the default Config.
+func New() *Stats {
+	return NewWithConfig(Config{})
+}
+
+// now returns mw.clock(), falling back to time.Now for a Stats built without
+// New/NewWithConfig.
+func (mw *Stats) now() time.Time {
+	if mw.clock == nil {
+		return time.Now()
+	}
+	return mw.clock()
 }
 
-// ResetResponseCounts reset the response counts
+// ResetResponseCounts reset the response counts. Kept for backward
+// compatibility; New()'s background collector now calls the same rotation
+// logic on a timer instead.
 func (mw *Stats) ResetResponseCounts() {
-	mw.mu.Lock()
-	defer mw.mu.Unlock()
-	mw.ResponseCounts = map[string]int{}
+	mw.rotate()
 }
 
 // Handler is a MiddlewareFunc makes Stats implement the Middleware interface.
@@ -57,48 +98,148 @@ func (mw *Stats) Handler(h fasthttp.RequestHandler) fasthttp.RequestHandler {
 
 // Begin starts a recorder
 func (mw *Stats) Begin(ctx *fasthttp.RequestCtx) (time.Time, *fasthttp.RequestCtx) {
-	start := time.Now()
+	start := mw.now()
 	ctx.SetStatusCode(200)
 	return start, ctx
 }
 
-// EndWithStatus closes the recorder with a specific status
-func (mw *Stats) EndWithStatus(start time.Time, status int) {
-	end := time.Now()
+// Options carries a pre-computed status code and request/response sizes for
+// callers that don't run through Handler/End and so have no *fasthttp.RequestCtx
+// to sample sizes from directly.
+type Options struct {
+	StatusCode int
+	BytesIn    int64
+	BytesOut   int64
+
+	// Method and Route, when set, group this request into the per-route
+	// breakdown (Data.Routes, TopRoutes). Both are left empty by
+	// EndWithStatus, so callers that only have a status code are simply
+	// excluded from that breakdown.
+	Method string
+	Route  string
+}
+
+// EndWithOptions closes the recorder using a pre-computed status code and
+// byte counts. EndWithStatus and End are thin wrappers around it.
+func (mw *Stats) EndWithOptions(start time.Time, opts Options) {
+	end := mw.now()
 
 	responseTime := end.Sub(start)
 
+	atomic.AddInt64(&mw.totalBytesIn, opts.BytesIn)
+	atomic.AddInt64(&mw.totalBytesOut, opts.BytesOut)
+	atomic.AddInt64(&mw.currentBytesIn, opts.BytesIn)
+	atomic.AddInt64(&mw.currentBytesOut, opts.BytesOut)
+
+	statusCode := fmt.Sprintf("%d", opts.StatusCode)
+
 	mw.mu.Lock()
 
 	defer mw.mu.Unlock()
 
-	statusCode := fmt.Sprintf("%d", status)
-
 	mw.ResponseCounts[statusCode]++
 	mw.TotalResponseCounts[statusCode]++
 	mw.TotalResponseTime = mw.TotalResponseTime.Add(responseTime)
+	mw.BytesInByStatus[statusCode] += opts.BytesIn
+	mw.BytesOutByStatus[statusCode] += opts.BytesOut
+	mw.recordRoute(opts, statusCode, responseTime)
+
+	mw.allTime[classOverall].record(responseTime)
+	if c := classify(opts.StatusCode); c < numStatusClasses {
+		mw.allTime[c].record(responseTime)
+	}
+	for _, w := range mw.windows {
+		w.record(opts.StatusCode, responseTime)
+	}
+}
+
+// Percentile returns the all-time q-th percentile response time across every
+// status code, e.g. Percentile(0.95) for p95.
+func (mw *Stats) Percentile(q float64) time.Duration {
+	return mw.allTime[classOverall].percentile(q)
+}
+
+// EndWithStatus closes the recorder with a specific status
+func (mw *Stats) EndWithStatus(start time.Time, status int) {
+	mw.EndWithOptions(start, Options{StatusCode: status})
 }
 
-// End closes the recorder with the recorder status
+// End closes the recorder with the recorder status, sampling request and
+// response sizes off of ctx.
 func (mw *Stats) End(start time.Time, ctx *fasthttp.RequestCtx) {
-	mw.EndWithStatus(start, ctx.Response.StatusCode())
+	bytesIn := int64(len(ctx.Request.Header.Header())) + int64(len(ctx.Request.Body()))
+
+	bytesOut := ctx.Response.Header.ContentLength()
+	if bytesOut <= 0 {
+		// unknown, chunked, or not yet set by the handler (the common case —
+		// ContentLength is only populated once fasthttp serializes the
+		// response after the middleware chain returns), fall back to the
+		// buffered body
+		bytesOut = len(ctx.Response.Body())
+	}
+	bytesOut += len(ctx.Response.Header.Header())
+
+	mw.EndWithOptions(start, Options{
+		StatusCode: ctx.Response.StatusCode(),
+		BytesIn:    bytesIn,
+		BytesOut:   int64(bytesOut),
+		Method:     string(ctx.Method()),
+		Route:      mw.routeExtractor(ctx),
+	})
 }
 
 // Data serializable structure
 type Data struct {
-	Pid                    int            `json:"pid"`
-	UpTime                 string         `json:"uptime"`
-	UpTimeSec              float64        `json:"uptime_sec"`
-	Time                   string         `json:"time"`
-	TimeUnix               int64          `json:"unixtime"`
-	StatusCodeCount        map[string]int `json:"status_code_count"`
-	TotalStatusCodeCount   map[string]int `json:"total_status_code_count"`
-	Count                  int            `json:"count"`
-	TotalCount             int            `json:"total_count"`
-	TotalResponseTime      string         `json:"total_response_time"`
-	TotalResponseTimeSec   float64        `json:"total_response_time_sec"`
-	AverageResponseTime    string         `json:"average_response_time"`
-	AverageResponseTimeSec float64        `json:"average_response_time_sec"`
+	Pid                    int              `json:"pid"`
+	UpTime                 string           `json:"uptime"`
+	UpTimeSec              float64          `json:"uptime_sec"`
+	Time                   string           `json:"time"`
+	TimeUnix               int64            `json:"unixtime"`
+	StatusCodeCount        map[string]int   `json:"status_code_count"`
+	TotalStatusCodeCount   map[string]int   `json:"total_status_code_count"`
+	Count                  int              `json:"count"`
+	TotalCount             int              `json:"total_count"`
+	TotalResponseTime      string           `json:"total_response_time"`
+	TotalResponseTimeSec   float64          `json:"total_response_time_sec"`
+	AverageResponseTime    string           `json:"average_response_time"`
+	AverageResponseTimeSec float64          `json:"average_response_time_sec"`
+	TotalBytesIn           int64            `json:"total_bytes_in"`
+	TotalBytesOut          int64            `json:"total_bytes_out"`
+	AverageBytesIn         float64          `json:"average_bytes_in"`
+	AverageBytesOut        float64          `json:"average_bytes_out"`
+	BytesInPerSecond       float64          `json:"bytes_in_per_second"`
+	BytesOutPerSecond      float64          `json:"bytes_out_per_second"`
+	BytesInByStatus        map[string]int64 `json:"bytes_in_by_status"`
+	BytesOutByStatus       map[string]int64 `json:"bytes_out_by_status"`
+	P50ResponseTime        string           `json:"p50_response_time"`
+	P95ResponseTime        string           `json:"p95_response_time"`
+	P99ResponseTime        string           `json:"p99_response_time"`
+	MaxResponseTime        string           `json:"max_response_time"`
+	P50ResponseTimeSec     float64          `json:"p50_response_time_sec"`
+	P95ResponseTimeSec     float64          `json:"p95_response_time_sec"`
+	P99ResponseTimeSec     float64          `json:"p99_response_time_sec"`
+
+	// LatencyWindows maps window label ("1m", "5m", "1h") to per-status-class
+	// latency stats over that rolling span.
+	LatencyWindows map[string]map[string]LatencyWindowStats `json:"latency_windows"`
+
+	// Routes maps method to route to that (method, route) pair's stats.
+	Routes map[string]map[string]RouteStat `json:"routes"`
+
+	// RecentIntervals holds the status code counts from each of the last
+	// len(RecentIntervals) completed reset intervals, oldest first.
+	RecentIntervals []map[string]int `json:"recent_intervals"`
+}
+
+// LatencyWindowStats summarizes one status class's response times over a
+// rolling window.
+type LatencyWindowStats struct {
+	Count           uint64  `json:"count"`
+	RatePerSecond   float64 `json:"rate_per_second"`
+	P50ResponseTime string  `json:"p50_response_time"`
+	P95ResponseTime string  `json:"p95_response_time"`
+	P99ResponseTime string  `json:"p99_response_time"`
+	MaxResponseTime string  `json:"max_response_time"`
 }
 
 // Data returns the data serializable structure
@@ -108,8 +249,10 @@ func (mw *Stats) Data() *Data {
 
 	responseCounts := make(map[string]int, len(mw.ResponseCounts))
 	totalResponseCounts := make(map[string]int, len(mw.TotalResponseCounts))
+	bytesInByStatus := make(map[string]int64, len(mw.BytesInByStatus))
+	bytesOutByStatus := make(map[string]int64, len(mw.BytesOutByStatus))
 
-	now := time.Now()
+	now := mw.now()
 
 	uptime := now.Sub(mw.Uptime)
 
@@ -125,6 +268,13 @@ func (mw *Stats) Data() *Data {
 		totalCount += count
 	}
 
+	for code, n := range mw.BytesInByStatus {
+		bytesInByStatus[code] = n
+	}
+	for code, n := range mw.BytesOutByStatus {
+		bytesOutByStatus[code] = n
+	}
+
 	totalResponseTime := mw.TotalResponseTime.Sub(time.Time{})
 
 	averageResponseTime := time.Duration(0)
@@ -133,8 +283,76 @@ func (mw *Stats) Data() *Data {
 		averageResponseTime = time.Duration(avgNs)
 	}
 
+	windowSeconds := now.Sub(mw.lastReset).Seconds()
+
+	recentIntervals := make([]map[string]int, len(mw.intervalBuckets))
+	for i := range mw.intervalBuckets {
+		idx := (mw.intervalPos + i) % len(mw.intervalBuckets)
+		src := mw.intervalBuckets[idx]
+		dst := make(map[string]int, len(src))
+		for code, n := range src {
+			dst[code] = n
+		}
+		recentIntervals[i] = dst
+	}
+
 	mw.mu.RUnlock()
 
+	totalBytesIn := atomic.LoadInt64(&mw.totalBytesIn)
+	totalBytesOut := atomic.LoadInt64(&mw.totalBytesOut)
+	currentBytesIn := atomic.LoadInt64(&mw.currentBytesIn)
+	currentBytesOut := atomic.LoadInt64(&mw.currentBytesOut)
+
+	var averageBytesIn, averageBytesOut float64
+	if totalCount > 0 {
+		averageBytesIn = float64(totalBytesIn) / float64(totalCount)
+		averageBytesOut = float64(totalBytesOut) / float64(totalCount)
+	}
+
+	var bytesInPerSecond, bytesOutPerSecond float64
+	if windowSeconds > 0 {
+		bytesInPerSecond = float64(currentBytesIn) / windowSeconds
+		bytesOutPerSecond = float64(currentBytesOut) / windowSeconds
+	}
+
+	overall := mw.allTime[classOverall]
+	p50 := overall.percentile(0.50)
+	p95 := overall.percentile(0.95)
+	p99 := overall.percentile(0.99)
+
+	latencyWindows := make(map[string]map[string]LatencyWindowStats, len(mw.windows))
+	for _, w := range mw.windows {
+		byClass := make(map[string]LatencyWindowStats, numStatusClasses)
+		spanSeconds := w.span().Seconds()
+		for c := statusClass(0); c < numStatusClasses; c++ {
+			h := w.merged(c)
+			count := h.total()
+			var rate float64
+			if spanSeconds > 0 {
+				rate = float64(count) / spanSeconds
+			}
+			byClass[statusClassNames[c]] = LatencyWindowStats{
+				Count:           count,
+				RatePerSecond:   rate,
+				P50ResponseTime: h.percentile(0.50).String(),
+				P95ResponseTime: h.percentile(0.95).String(),
+				P99ResponseTime: h.percentile(0.99).String(),
+				MaxResponseTime: h.max().String(),
+			}
+		}
+		latencyWindows[w.label] = byClass
+	}
+
+	routes := make(map[string]map[string]RouteStat)
+	for _, rs := range mw.routeSnapshot() {
+		byRoute, ok := routes[rs.Method]
+		if !ok {
+			byRoute = map[string]RouteStat{}
+			routes[rs.Method] = byRoute
+		}
+		byRoute[rs.Route] = rs
+	}
+
 	r := &Data{
 		Pid:                    mw.Pid,
 		UpTime:                 uptime.String(),
@@ -149,7 +367,25 @@ func (mw *Stats) Data() *Data {
 		TotalResponseTimeSec:   totalResponseTime.Seconds(),
 		AverageResponseTime:    averageResponseTime.String(),
 		AverageResponseTimeSec: averageResponseTime.Seconds(),
+		TotalBytesIn:           totalBytesIn,
+		TotalBytesOut:          totalBytesOut,
+		AverageBytesIn:         averageBytesIn,
+		AverageBytesOut:        averageBytesOut,
+		BytesInPerSecond:       bytesInPerSecond,
+		BytesOutPerSecond:      bytesOutPerSecond,
+		BytesInByStatus:        bytesInByStatus,
+		BytesOutByStatus:       bytesOutByStatus,
+		P50ResponseTime:        p50.String(),
+		P95ResponseTime:        p95.String(),
+		P99ResponseTime:        p99.String(),
+		MaxResponseTime:        overall.max().String(),
+		P50ResponseTimeSec:     p50.Seconds(),
+		P95ResponseTimeSec:     p95.Seconds(),
+		P99ResponseTimeSec:     p99.Seconds(),
+		LatencyWindows:         latencyWindows,
+		Routes:                 routes,
+		RecentIntervals:        recentIntervals,
 	}
 
 	return r
-}
\ No newline at end of file
+}
@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestEndCountsBufferedBodyWhenContentLengthUnset(t *testing.T) {
+	mw := New()
+	defer mw.Close()
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/")
+	ctx.Response.SetBodyString("this is a response body of known length")
+
+	headerLen := len(ctx.Response.Header.Header())
+	wantBytesOut := int64(headerLen + ctx.Response.Header.ContentLength())
+
+	mw.End(time.Now(), ctx)
+
+	data := mw.Data()
+	if data.TotalBytesOut <= wantBytesOut {
+		t.Errorf("TotalBytesOut = %d, want more than the header-only length %d (body must be counted)",
+			data.TotalBytesOut, wantBytesOut)
+	}
+}
+
+func TestEndTracksBytesIn(t *testing.T) {
+	mw := New()
+	defer mw.Close()
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/")
+	ctx.Request.SetBodyString("request body")
+
+	mw.End(time.Now(), ctx)
+
+	data := mw.Data()
+	if data.TotalBytesIn <= int64(len("request body")) {
+		t.Errorf("TotalBytesIn = %d, want more than the body length alone", data.TotalBytesIn)
+	}
+}